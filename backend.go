@@ -0,0 +1,21 @@
+package recordtrie
+
+// trieBackend abstracts the underlying storage engine used by RecordTrie.
+// It operates purely in terms of trie keys (the key\xFFvalue encoding
+// produced by buildTrieKey), so RecordTrie itself never needs to know
+// which engine is in use.
+type trieBackend interface {
+	// Build constructs the backend's index from the full set of trie keys.
+	Build(trieKeys []string)
+
+	// PredictiveSearch calls fn for every trie key starting with prefix,
+	// in the backend's natural iteration order, until fn returns false.
+	PredictiveSearch(prefix string, fn func(trieKey string) bool)
+
+	// Save persists the backend to path.
+	Save(path string) error
+
+	// Mmap loads the backend from path. Backends that can't actually
+	// memory-map their format are free to read it into memory instead.
+	Mmap(path string) error
+}
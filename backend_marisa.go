@@ -0,0 +1,63 @@
+//go:build cgo
+
+package recordtrie
+
+import (
+	"fmt"
+
+	"github.com/1player/go-marisa"
+)
+
+// marisaBackend is the default trieBackend, implemented on top of the
+// cgo MARISA library. It is the fastest and most compact option, but
+// requires a native library and a cgo-capable build environment.
+type marisaBackend struct {
+	t marisa.Trie
+}
+
+func newMarisaBackend() *marisaBackend {
+	return &marisaBackend{
+		t: marisa.NewTrie(),
+	}
+}
+
+func (b *marisaBackend) Build(trieKeys []string) {
+	ks := marisa.NewKeyset()
+	for _, trieKey := range trieKeys {
+		ks.PushBackString(trieKey)
+	}
+	b.t.Build(ks)
+}
+
+func (b *marisaBackend) PredictiveSearch(prefix string, fn func(trieKey string) bool) {
+	a := marisa.NewAgent()
+	a.SetQueryString(prefix)
+
+	for b.t.PredictiveSearch(a) {
+		if !fn(a.Key().Str()) {
+			break
+		}
+	}
+}
+
+func (b *marisaBackend) Save(path string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	b.t.Save(path)
+	return
+}
+
+func (b *marisaBackend) Mmap(path string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	b.t.Mmap(path)
+	return
+}
@@ -0,0 +1,34 @@
+//go:build !cgo
+
+package recordtrie
+
+import "fmt"
+
+// marisaBackend stands in for the real, cgo-based implementation
+// (backend_marisa.go) when the package is built without cgo, e.g. with
+// CGO_ENABLED=0 for a cross-compile to Windows, ARM or WASM. Callers on
+// such platforms should pick a non-cgo backend instead, such as the
+// pure-Go patricia one, via NewWithBackend.
+type marisaBackend struct{}
+
+func newMarisaBackend() *marisaBackend {
+	return &marisaBackend{}
+}
+
+const marisaUnavailableMsg = "recordtrie: the MARISA backend requires cgo, and this build was compiled without it; use NewWithBackend with a non-cgo backend such as the patricia one"
+
+func (b *marisaBackend) Build(trieKeys []string) {
+	panic(marisaUnavailableMsg)
+}
+
+func (b *marisaBackend) PredictiveSearch(prefix string, fn func(trieKey string) bool) {
+	panic(marisaUnavailableMsg)
+}
+
+func (b *marisaBackend) Save(path string) error {
+	return fmt.Errorf(marisaUnavailableMsg)
+}
+
+func (b *marisaBackend) Mmap(path string) error {
+	return fmt.Errorf(marisaUnavailableMsg)
+}
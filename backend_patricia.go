@@ -0,0 +1,225 @@
+package recordtrie
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// patriciaMagic identifies files written by patriciaBackend.Save, so that
+// NewFromFileWithBackend(path, &patriciaBackend{}) can fail fast with a
+// clear error instead of garbage if handed a file in another format.
+const patriciaMagic = "RTPT"
+
+// patriciaVersion is bumped whenever the on-disk node encoding changes.
+const patriciaVersion = 1
+
+// patriciaNode is a single node of a compressed (patricia/radix) trie.
+// Each node stores the edge label leading to it rather than a single
+// character, so long chains of single-child nodes collapse into one
+// edge, as in github.com/tchap/go-patricia.
+type patriciaNode struct {
+	Label    string
+	Terminal bool
+	Children []*patriciaNode
+}
+
+// patriciaBackend is a pure-Go trieBackend built on an in-memory
+// compressed trie. It is slower and less compact than marisaBackend, but
+// needs no cgo or native library, so it works anywhere the Go toolchain
+// does (Windows, cross-compiled ARM, WASM, ...).
+type patriciaBackend struct {
+	root *patriciaNode
+}
+
+func newPatriciaBackend() *patriciaBackend {
+	return &patriciaBackend{root: &patriciaNode{}}
+}
+
+func (b *patriciaBackend) Build(trieKeys []string) {
+	keys := append([]string(nil), trieKeys...)
+	sort.Strings(keys)
+
+	root := &patriciaNode{}
+	for _, key := range keys {
+		root.insert(key)
+	}
+	b.root = root
+}
+
+func (n *patriciaNode) insert(key string) {
+	for _, child := range n.Children {
+		common := commonPrefixLen(child.Label, key)
+		if common == 0 {
+			continue
+		}
+
+		if common == len(child.Label) {
+			if common == len(key) {
+				child.Terminal = true
+			} else {
+				child.insert(key[common:])
+			}
+			return
+		}
+
+		// key and child.Label diverge partway through the edge: split
+		// child into a shared prefix node with two children below it.
+		tail := &patriciaNode{
+			Label:    child.Label[common:],
+			Terminal: child.Terminal,
+			Children: child.Children,
+		}
+		child.Label = child.Label[:common]
+		child.Terminal = false
+		child.Children = []*patriciaNode{tail}
+
+		if common == len(key) {
+			child.Terminal = true
+		} else {
+			child.Children = append(child.Children, &patriciaNode{Label: key[common:], Terminal: true})
+		}
+		return
+	}
+
+	n.Children = append(n.Children, &patriciaNode{Label: key, Terminal: true})
+}
+
+func commonPrefixLen(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+	i := 0
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// PredictiveSearch walks down to the node(s) covering prefix, then emits
+// every key stored beneath it, in sorted order.
+func (b *patriciaBackend) PredictiveSearch(prefix string, fn func(trieKey string) bool) {
+	node, matched := b.root.descend(prefix)
+	if node == nil {
+		return
+	}
+
+	node.walk(matched, fn)
+}
+
+// descend follows edges matching prefix and returns the node at which
+// prefix is fully consumed, together with the key built up to reach it.
+func (n *patriciaNode) descend(prefix string) (*patriciaNode, string) {
+	if prefix == "" {
+		return n, ""
+	}
+
+	for _, child := range n.Children {
+		common := commonPrefixLen(child.Label, prefix)
+		if common == 0 {
+			continue
+		}
+
+		if common < len(child.Label) {
+			if common == len(prefix) {
+				// prefix ends partway through this edge: every key under
+				// child (including child itself) matches it.
+				return child, child.Label
+			}
+			// prefix diverges partway through this edge: no match.
+			return nil, ""
+		}
+
+		// common == len(child.Label): the whole edge matches.
+		if common == len(prefix) {
+			return child, child.Label
+		}
+
+		sub, matched := child.descend(prefix[common:])
+		if sub == nil {
+			return nil, ""
+		}
+		return sub, child.Label + matched
+	}
+
+	return nil, ""
+}
+
+func (n *patriciaNode) walk(built string, fn func(trieKey string) bool) bool {
+	if n.Terminal {
+		if !fn(built) {
+			return false
+		}
+	}
+
+	for _, child := range n.Children {
+		if !child.walk(built+child.Label, fn) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// patriciaFile is the on-disk container written by Save: a short
+// self-describing header followed by the gob-encoded node tree, so a
+// patriciaBackend file can be told apart from other backends' formats.
+type patriciaFile struct {
+	Root *patriciaNode
+}
+
+func (b *patriciaBackend) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(patriciaMagic); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(w).Encode(struct {
+		Version int
+		File    patriciaFile
+	}{patriciaVersion, patriciaFile{Root: b.root}}); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func (b *patriciaBackend) Mmap(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(patriciaMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return err
+	}
+	if string(magic) != patriciaMagic {
+		return fmt.Errorf("recordtrie: not a patricia backend file: %s", path)
+	}
+
+	var payload struct {
+		Version int
+		File    patriciaFile
+	}
+	if err := gob.NewDecoder(r).Decode(&payload); err != nil {
+		return err
+	}
+	if payload.Version != patriciaVersion {
+		return fmt.Errorf("recordtrie: unsupported patricia backend version %d", payload.Version)
+	}
+
+	b.root = payload.File.Root
+	return nil
+}
@@ -0,0 +1,91 @@
+package recordtrie
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPatriciaBackendFind(t *testing.T) {
+	records := []Record{
+		{"foo", "bar"},
+		{"abc", "def"},
+		{"foo", "baz"},
+	}
+
+	r := NewWithBackend(records, newPatriciaBackend())
+
+	tests := []struct {
+		key    string
+		values []string
+	}{
+		{"foo", []string{"bar", "baz"}},
+		{"abc", []string{"def"}},
+		{"def", []string{}},
+	}
+
+	for _, test := range tests {
+		v := r.Find(test.key)
+		if !compareStringSlices(v, test.values) {
+			t.Errorf("RecordTrie.Find(%q): got %v expected %v\n", test.key,
+				v, test.values)
+		}
+	}
+}
+
+func TestPatriciaBackendKeysStartingWith(t *testing.T) {
+	records := []Record{
+		{"foo", "bar"},
+		{"abc", "def"},
+		{"a", "apple"},
+		{"ac", "acorn"},
+		{"foo", "baz"},
+	}
+
+	r := NewWithBackend(records, newPatriciaBackend())
+
+	tests := []struct {
+		query string
+		keys  []string
+	}{
+		{"foo", []string{"foo", "foo"}},
+		{"a", []string{"a", "ac", "abc"}},
+		{"ab", []string{"abc"}},
+		{"def", []string{}},
+	}
+
+	for _, test := range tests {
+		keys := r.KeysStartingWith(test.query)
+		if !compareStringSlices(keys, test.keys) {
+			t.Errorf("RecordTrie.KeysStartingWith(%q): got %v expected %v\n", test.query,
+				keys, test.keys)
+		}
+	}
+}
+
+func TestPatriciaBackendLoadSave(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "testPatriciaTrie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFilePath := tmpFile.Name()
+	defer os.Remove(tmpFilePath)
+
+	r := NewWithBackend([]Record{
+		{"abc", "def"},
+	}, newPatriciaBackend())
+
+	if err := r.Save(tmpFilePath); err != nil {
+		t.Fatal("RecordTrie.Save", err)
+	}
+
+	r, err = NewFromFileWithBackend(tmpFilePath, newPatriciaBackend())
+	if err != nil {
+		t.Fatal("NewFromFileWithBackend", err)
+	}
+
+	v := r.Find("abc")
+	if len(v) != 1 || v[0] != "def" {
+		t.Errorf("RecordTrie backend round-trip: unexpected data")
+	}
+}
@@ -0,0 +1,218 @@
+package recordtrie
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// compactContainerMagic identifies the container file written by Save
+// for a compact RecordTrie, so NewFromFile can tell it apart from a
+// plain backend file and auto-detect which layout to load.
+const compactContainerMagic = "RTCF"
+
+// compactContainerVersion is bumped whenever the container's layout changes.
+const compactContainerVersion = 1
+
+// compactTrieFileSuffix names the sidecar file holding the primary
+// key trie of a compact RecordTrie; the container file itself only
+// holds the header and the deduplicated values.
+const compactTrieFileSuffix = ".trie"
+
+// compactValueSeparator joins the value IDs stored for a single key in
+// the compact trie's value position. It must not appear in strconv's
+// output for a non-negative int, so any ASCII character other than a
+// digit works.
+const compactValueSeparator = ","
+
+// compactStore holds the deduplicated values of a compact RecordTrie.
+// The primary trie maps each unique key to a list of indexes into Values,
+// instead of repeating the key once per value.
+type compactStore struct {
+	Values []string
+}
+
+func encodeValueIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, compactValueSeparator)
+}
+
+func decodeValueIDs(encoded string) ([]int, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(encoded, compactValueSeparator)
+	ids := make([]int, len(parts))
+	for i, part := range parts {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("recordtrie: corrupt compact value IDs %q: %v", encoded, err)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// resolveValueIDs decodes encoded and looks each ID up in s.Values,
+// returning an error instead of panicking if encoded is malformed or an
+// ID falls outside the values table, e.g. due to on-disk corruption.
+func (s *compactStore) resolveValueIDs(encoded string) ([]string, error) {
+	ids, err := decodeValueIDs(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		if id < 0 || id >= len(s.Values) {
+			return nil, fmt.Errorf("recordtrie: compact value ID %d out of range (have %d values)", id, len(s.Values))
+		}
+		values[i] = s.Values[id]
+	}
+	return values, nil
+}
+
+// validateCompactValues walks every key's encoded value IDs and confirms
+// they decode cleanly, so a corrupt compact container is rejected by
+// NewFromFileWithBackend up front instead of panicking the first time a
+// caller queries it.
+func validateCompactValues(r *RecordTrie) error {
+	var firstErr error
+
+	r.iter("", func(key, encoded string) bool {
+		if _, err := r.compact.resolveValueIDs(encoded); err != nil {
+			firstErr = fmt.Errorf("recordtrie: key %q: %w", key, err)
+			return false
+		}
+		return true
+	})
+
+	return firstErr
+}
+
+// Create a new RecordTrie from a list of Records, using a compact layout
+// that stores each unique key once, alongside a run of value IDs, and
+// deduplicates values in a separate table. This is worth it for datasets
+// where values dominate, such as inverted indexes or tag-to-document maps.
+func NewCompact(records []Record) *RecordTrie {
+	return NewCompactWithOptions(records, Options{})
+}
+
+// Create a new compact RecordTrie, using the given Options.
+func NewCompactWithOptions(records []Record, opts Options) *RecordTrie {
+	opts = opts.withDefaults()
+
+	valueIDs := make(map[string]int)
+	var values []string
+
+	var keyOrder []string
+	keyToIDs := make(map[string][]int)
+
+	for _, record := range records {
+		id, ok := valueIDs[record.Value]
+		if !ok {
+			id = len(values)
+			valueIDs[record.Value] = id
+			values = append(values, record.Value)
+		}
+
+		if _, ok := keyToIDs[record.Key]; !ok {
+			keyOrder = append(keyOrder, record.Key)
+		}
+		keyToIDs[record.Key] = append(keyToIDs[record.Key], id)
+	}
+
+	trieKeys := make([]string, len(keyOrder))
+	for i, key := range keyOrder {
+		trieKeys[i] = buildTrieKey(key, encodeValueIDs(keyToIDs[key]))
+	}
+
+	r := &RecordTrie{
+		backend: opts.Backend,
+		compact: &compactStore{Values: values},
+	}
+	r.backend.Build(trieKeys)
+	r.ngrams = buildNgramIndex(keyOrder, opts.NgramSize)
+
+	return r
+}
+
+// isCompactContainer reports whether path starts with the compact
+// container's magic header.
+func isCompactContainer(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(compactContainerMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return string(magic) == compactContainerMagic, nil
+}
+
+func saveCompactContainer(path string, store *compactStore) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(compactContainerMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(compactContainerVersion)); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(w).Encode(store); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func loadCompactContainer(path string) (*compactStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	magic := make([]byte, len(compactContainerMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+
+	var version uint32
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != compactContainerVersion {
+		return nil, fmt.Errorf("recordtrie: unsupported compact container version %d", version)
+	}
+
+	store := &compactStore{}
+	if err := gob.NewDecoder(r).Decode(store); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
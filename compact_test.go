@@ -0,0 +1,194 @@
+package recordtrie
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCompactFind(t *testing.T) {
+	records := []Record{
+		{"foo", "bar"},
+		{"abc", "def"},
+		{"foo", "baz"},
+	}
+
+	r := NewCompactWithOptions(records, Options{Backend: newPatriciaBackend()})
+
+	tests := []struct {
+		key    string
+		values []string
+	}{
+		{"foo", []string{"bar", "baz"}},
+		{"abc", []string{"def"}},
+		{"def", []string{}},
+	}
+
+	for _, test := range tests {
+		v := r.Find(test.key)
+		if !compareStringSlices(v, test.values) {
+			t.Errorf("RecordTrie.Find(%q): got %v expected %v\n", test.key,
+				v, test.values)
+		}
+	}
+}
+
+func TestCompactDedupesValues(t *testing.T) {
+	records := []Record{
+		{"a", "shared"},
+		{"b", "shared"},
+	}
+
+	r := NewCompactWithOptions(records, Options{Backend: newPatriciaBackend()})
+
+	if len(r.compact.Values) != 1 {
+		t.Errorf("NewCompact: expected 1 unique value, got %d: %v", len(r.compact.Values), r.compact.Values)
+	}
+}
+
+func TestCompactRecordsAndKeysStartingWith(t *testing.T) {
+	records := []Record{
+		{"foo", "bar"},
+		{"foobar", "baz"},
+	}
+
+	r := NewCompactWithOptions(records, Options{Backend: newPatriciaBackend()})
+
+	if keys := r.KeysStartingWith("foo"); !compareStringSlices(keys, []string{"foo", "foobar"}) {
+		t.Errorf("RecordTrie.KeysStartingWith(\"foo\"): got %v", keys)
+	}
+
+	got := r.Records()
+	if len(got) != len(records) {
+		t.Errorf("RecordTrie.Records(): got %v, expected %d records", got, len(records))
+	}
+}
+
+func TestCompactLoadSave(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "testCompactTrie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFilePath := tmpFile.Name()
+	defer os.Remove(tmpFilePath)
+	defer os.Remove(tmpFilePath + compactTrieFileSuffix)
+	defer os.Remove(tmpFilePath + ngramFileSuffix)
+
+	r := NewCompactWithOptions([]Record{
+		{"foo", "bar"},
+		{"abc", "bar"},
+	}, Options{Backend: newPatriciaBackend()})
+
+	if err := r.Save(tmpFilePath); err != nil {
+		t.Fatal("RecordTrie.Save", err)
+	}
+
+	r, err = NewFromFileWithBackend(tmpFilePath, newPatriciaBackend())
+	if err != nil {
+		t.Fatal("NewFromFileWithBackend", err)
+	}
+
+	if v := r.Find("foo"); !compareStringSlices(v, []string{"bar"}) {
+		t.Errorf("RecordTrie.Find(\"foo\") after load: got %v", v)
+	}
+	if len(r.compact.Values) != 1 {
+		t.Errorf("compact.Values after load: got %v, expected 1 deduped value", r.compact.Values)
+	}
+}
+
+func TestNewFromFileWithBackendRejectsCorruptCompactValues(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "testCompactTrie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFilePath := tmpFile.Name()
+	defer os.Remove(tmpFilePath)
+	defer os.Remove(tmpFilePath + compactTrieFileSuffix)
+	defer os.Remove(tmpFilePath + ngramFileSuffix)
+
+	r := NewCompactWithOptions([]Record{
+		{"foo", "bar"},
+	}, Options{Backend: newPatriciaBackend()})
+
+	if err := r.Save(tmpFilePath); err != nil {
+		t.Fatal("RecordTrie.Save", err)
+	}
+
+	// Truncate the values table so the one encoded ID the trie holds
+	// points past the end of it, simulating on-disk corruption.
+	if err := saveCompactContainer(tmpFilePath, &compactStore{}); err != nil {
+		t.Fatal("saveCompactContainer", err)
+	}
+
+	if _, err := NewFromFileWithBackend(tmpFilePath, newPatriciaBackend()); err == nil {
+		t.Error("NewFromFileWithBackend: expected an error loading a corrupt compact container, got nil")
+	}
+}
+
+// BenchmarkPlainVsCompactSize reports the on-disk size of the plain and
+// compact layouts for a dataset where many keys share few values, which
+// is the case the compact layout is meant for.
+func BenchmarkPlainVsCompactSize(b *testing.B) {
+	// An inverted-index shaped dataset: many distinct keys, each tagged
+	// with several values drawn from a small pool. This is the case
+	// NewCompact targets, where the plain layout pays for a full copy
+	// of the key once per value instead of once per key.
+	const numKeys = 200
+	const valuesPerKey = 5
+	const numUniqueValues = 20
+
+	var records []Record
+	for k := 0; k < numKeys; k++ {
+		key := fmt.Sprintf("doc-%05d", k)
+		for v := 0; v < valuesPerKey; v++ {
+			records = append(records, Record{
+				Key:   key,
+				Value: fmt.Sprintf("tag-%03d", (k*valuesPerKey+v)%numUniqueValues),
+			})
+		}
+	}
+
+	plainDir, err := ioutil.TempDir("", "plainTrie")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(plainDir)
+
+	compactDir, err := ioutil.TempDir("", "compactTrie")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(compactDir)
+
+	plain := NewWithBackend(records, newPatriciaBackend())
+	plainPath := plainDir + "/trie"
+	if err := plain.Save(plainPath); err != nil {
+		b.Fatal(err)
+	}
+
+	compact := NewCompactWithOptions(records, Options{Backend: newPatriciaBackend()})
+	compactPath := compactDir + "/trie"
+	if err := compact.Save(compactPath); err != nil {
+		b.Fatal(err)
+	}
+
+	plainSize := fileSize(b, plainPath)
+	compactSize := fileSize(b, compactPath) + fileSize(b, compactPath+compactTrieFileSuffix)
+
+	b.ReportMetric(float64(plainSize), "plain-bytes")
+	b.ReportMetric(float64(compactSize), "compact-bytes")
+
+	for i := 0; i < b.N; i++ {
+		plain.Find(records[0].Key)
+		compact.Find(records[0].Key)
+	}
+}
+
+func fileSize(b *testing.B, path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return info.Size()
+}
@@ -0,0 +1,147 @@
+package recordtrie
+
+// FindFuzzy returns every record whose key is within maxEdits
+// insertions/deletions/substitutions of key. Distance is computed
+// against the key only, never against the value.
+func (r *RecordTrie) FindFuzzy(key string, maxEdits int) []Record {
+	var records []Record
+
+	r.fuzzySearch(key, maxEdits, func(trieKey string) bool {
+		k, v := splitTrieKey(trieKey)
+		for _, value := range r.resolveValues(v) {
+			records = append(records, Record{k, value})
+		}
+		return true
+	})
+
+	return records
+}
+
+// KeysWithinEditDistance returns every distinct key within maxEdits
+// insertions/deletions/substitutions of prefix.
+func (r *RecordTrie) KeysWithinEditDistance(prefix string, maxEdits int) []string {
+	var keys []string
+	seen := make(map[string]bool)
+
+	r.fuzzySearch(prefix, maxEdits, func(trieKey string) bool {
+		k, _ := splitTrieKey(trieKey)
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+		return true
+	})
+
+	return keys
+}
+
+// fuzzyFrame is one step of the fuzzySearch walk: a key prefix built so
+// far, together with the Levenshtein automaton row for it.
+type fuzzyFrame struct {
+	prefix string
+	row    []int
+}
+
+// fuzzySearch finds every trie key whose key portion is within maxEdits
+// of query. Since the backend only exposes PredictiveSearch rather than
+// direct access to a node's children, it drives the search by extending
+// candidate prefixes byte by byte, using a Levenshtein automaton (in row
+// form, as in the classic Wagner-Fischer algorithm) to reject any prefix
+// that can no longer lead to a match within maxEdits.
+func (r *RecordTrie) fuzzySearch(query string, maxEdits int, fn func(trieKey string) bool) {
+	queue := []fuzzyFrame{{prefix: "", row: levenshteinFirstRow(query)}}
+
+	for len(queue) > 0 {
+		frame := queue[0]
+		queue = queue[1:]
+
+		nextBytes := make(map[byte]bool)
+		keepGoing := true
+
+		r.backend.PredictiveSearch(frame.prefix, func(trieKey string) bool {
+			if len(trieKey) == len(frame.prefix) {
+				// A key was stored with no value at all; treat the end
+				// of the trie key the same as hitting the separator.
+				if frame.row[len(query)] <= maxEdits {
+					keepGoing = fn(trieKey)
+				}
+				return keepGoing
+			}
+
+			b := trieKey[len(frame.prefix)]
+			if b == KV_SEPARATOR[0] {
+				if frame.row[len(query)] <= maxEdits {
+					keepGoing = fn(trieKey)
+				}
+			} else {
+				nextBytes[b] = true
+			}
+
+			return keepGoing
+		})
+
+		if !keepGoing {
+			return
+		}
+
+		for b := range nextBytes {
+			row := levenshteinNextRow(frame.row, query, b)
+			if levenshteinRowMin(row) > maxEdits {
+				continue
+			}
+			queue = append(queue, fuzzyFrame{prefix: frame.prefix + string(b), row: row})
+		}
+	}
+}
+
+// levenshteinFirstRow is the automaton row for the empty prefix: the
+// cost of turning query into "" is just deleting every character of query.
+func levenshteinFirstRow(query string) []int {
+	row := make([]int, len(query)+1)
+	for i := range row {
+		row[i] = i
+	}
+	return row
+}
+
+// levenshteinNextRow extends prevRow (the row for some prefix p) to the
+// row for p+string(c).
+func levenshteinNextRow(prevRow []int, query string, c byte) []int {
+	row := make([]int, len(query)+1)
+	row[0] = prevRow[0] + 1
+
+	for i := 1; i <= len(query); i++ {
+		cost := 1
+		if query[i-1] == c {
+			cost = 0
+		}
+
+		deletion := prevRow[i] + 1
+		insertion := row[i-1] + 1
+		substitution := prevRow[i-1] + cost
+
+		row[i] = minInt(deletion, minInt(insertion, substitution))
+	}
+
+	return row
+}
+
+// levenshteinRowMin is the smallest distance reachable from this row by
+// appending more characters; if it already exceeds maxEdits, no
+// continuation of this prefix can ever match.
+func levenshteinRowMin(row []int) int {
+	min := row[0]
+	for _, v := range row[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,49 @@
+package recordtrie
+
+import "testing"
+
+func TestFindFuzzy(t *testing.T) {
+	records := []Record{
+		{"cat", "a"},
+		{"cats", "b"},
+		{"bat", "c"},
+		{"dog", "d"},
+	}
+
+	r := NewWithBackend(records, newPatriciaBackend())
+
+	found := r.FindFuzzy("cat", 1)
+
+	keys := make(map[string]bool)
+	for _, rec := range found {
+		keys[rec.Key] = true
+	}
+
+	if !keys["cat"] || !keys["cats"] || !keys["bat"] {
+		t.Errorf("FindFuzzy(\"cat\", 1): expected cat, cats and bat in %v", found)
+	}
+	if keys["dog"] {
+		t.Errorf("FindFuzzy(\"cat\", 1): did not expect \"dog\" in %v", found)
+	}
+}
+
+func TestKeysWithinEditDistance(t *testing.T) {
+	records := []Record{
+		{"color", "a"},
+		{"colour", "b"},
+		{"collect", "c"},
+	}
+
+	r := NewWithBackend(records, newPatriciaBackend())
+
+	// "colour" (6 letters) is one insertion away from "color" (5 letters).
+	keys := r.KeysWithinEditDistance("color", 1)
+	if !compareStringSlices(keys, []string{"color", "colour"}) {
+		t.Errorf("KeysWithinEditDistance(\"color\", 1): got %v", keys)
+	}
+
+	exact := r.KeysWithinEditDistance("color", 0)
+	if !compareStringSlices(exact, []string{"color"}) {
+		t.Errorf("KeysWithinEditDistance(\"color\", 0): got %v", exact)
+	}
+}
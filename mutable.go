@@ -0,0 +1,224 @@
+package recordtrie
+
+import (
+	"os"
+	"strings"
+)
+
+// MutableRecordTrie wraps a read-only RecordTrie with an in-memory
+// overlay of inserts and tombstoned deletes. Find, Exists and
+// KeysStartingWith merge the two layers transparently, so long-running
+// services can accumulate updates without a full rebuild on every change.
+// Call Compact periodically to fold the overlay back into a fresh,
+// on-disk RecordTrie.
+type MutableRecordTrie struct {
+	base *RecordTrie
+
+	// newBackend creates the trieBackend Compact rebuilds into.
+	newBackend func() trieBackend
+
+	// added holds records inserted since base was built, or since the
+	// last Compact.
+	added []Record
+
+	// deleted tombstones individual (key, value) pairs.
+	deleted map[Record]bool
+
+	// deletedAll tombstones every base value for a key.
+	deletedAll map[string]bool
+}
+
+// NewMutable wraps base in a MutableRecordTrie with an empty overlay.
+// Compact rebuilds using the default MARISA backend.
+func NewMutable(base *RecordTrie) *MutableRecordTrie {
+	return NewMutableWithBackend(base, func() trieBackend { return newMarisaBackend() })
+}
+
+// NewMutableWithBackend wraps base in a MutableRecordTrie whose Compact
+// rebuilds using a fresh backend from newBackend, instead of the
+// default MARISA backend. This is how callers using, say, the patricia
+// backend for base keep using it across compactions.
+func NewMutableWithBackend(base *RecordTrie, newBackend func() trieBackend) *MutableRecordTrie {
+	return &MutableRecordTrie{
+		base:       base,
+		newBackend: newBackend,
+		deleted:    make(map[Record]bool),
+		deletedAll: make(map[string]bool),
+	}
+}
+
+// NewMutableFromFile loads base from path (the same way NewFromFile
+// does, typically via mmap) and wraps it in a MutableRecordTrie.
+func NewMutableFromFile(path string) (*MutableRecordTrie, error) {
+	base, err := NewFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMutable(base), nil
+}
+
+// Add inserts a record into the overlay.
+func (m *MutableRecordTrie) Add(record Record) {
+	delete(m.deleted, record)
+
+	if !m.deletedAll[record.Key] && m.baseHasValue(record) {
+		// base already makes this record visible; appending it to the
+		// overlay too would duplicate it in Find/Records.
+		return
+	}
+
+	m.added = append(m.added, record)
+}
+
+// baseHasValue reports whether the base trie already stores value
+// under key.
+func (m *MutableRecordTrie) baseHasValue(record Record) bool {
+	for _, v := range m.base.Find(record.Key) {
+		if v == record.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete tombstones a single (key, value) pair, whether it lives in the
+// base trie or was itself added to the overlay.
+func (m *MutableRecordTrie) Delete(key, value string) {
+	record := Record{key, value}
+	m.deleted[record] = true
+	m.added = removeRecord(m.added, record)
+}
+
+// DeleteAll tombstones every value stored under key.
+func (m *MutableRecordTrie) DeleteAll(key string) {
+	m.deletedAll[key] = true
+	m.added = removeRecordsForKey(m.added, key)
+}
+
+func removeRecord(records []Record, target Record) []Record {
+	kept := records[:0]
+	for _, r := range records {
+		if r != target {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func removeRecordsForKey(records []Record, key string) []Record {
+	kept := records[:0]
+	for _, r := range records {
+		if r.Key != key {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// Exists checks whether the key exists in the merged view.
+func (m *MutableRecordTrie) Exists(key string) bool {
+	return len(m.Find(key)) > 0
+}
+
+// Find retrieves the merged values list from the trie, given a key.
+func (m *MutableRecordTrie) Find(key string) []string {
+	var values []string
+
+	if !m.deletedAll[key] {
+		for _, v := range m.base.Find(key) {
+			if !m.deleted[Record{key, v}] {
+				values = append(values, v)
+			}
+		}
+	}
+
+	for _, r := range m.added {
+		if r.Key == key {
+			values = append(values, r.Value)
+		}
+	}
+
+	return values
+}
+
+// KeysStartingWith returns every key starting with keyPrefix that still
+// has at least one value in the merged view.
+func (m *MutableRecordTrie) KeysStartingWith(keyPrefix string) []string {
+	var keys []string
+	seen := make(map[string]bool)
+
+	for _, k := range m.base.KeysStartingWith(keyPrefix) {
+		if seen[k] || m.deletedAll[k] {
+			continue
+		}
+		seen[k] = true
+		if len(m.Find(k)) > 0 {
+			keys = append(keys, k)
+		}
+	}
+
+	for _, r := range m.added {
+		if !seen[r.Key] && strings.HasPrefix(r.Key, keyPrefix) {
+			seen[r.Key] = true
+			keys = append(keys, r.Key)
+		}
+	}
+
+	return keys
+}
+
+// Records returns every (key, value) pair in the merged view.
+func (m *MutableRecordTrie) Records() []Record {
+	var records []Record
+
+	for _, r := range m.base.Records() {
+		if m.deletedAll[r.Key] || m.deleted[r] {
+			continue
+		}
+		records = append(records, r)
+	}
+
+	return append(records, m.added...)
+}
+
+// Compact rebuilds a single fresh RecordTrie from the merged view and
+// replaces the file at path (writing to a temporary file, then renaming
+// it into place), the same dirty-tracking-and-sync pattern used by
+// other on-disk tries. Once Compact returns, the MutableRecordTrie's
+// overlay is empty and its base is the freshly written trie.
+//
+// The primary trie and its n-gram sidecar are two separate files, so
+// they can't be swapped in by a single rename; Compact renames the
+// primary trie into place first, then the sidecar. A concurrent
+// NewFromFileWithBackend(path, ...) that lands in between sees the new
+// trie paired with the old n-gram index: Find, Exists and
+// KeysStartingWith are fully up to date, while KeysContaining and
+// RecordsContaining may still miss or misreport keys added since the
+// last Compact, until the second rename lands.
+func (m *MutableRecordTrie) Compact(path string) error {
+	fresh := NewWithBackend(m.Records(), m.newBackend())
+
+	tmpPath := path + ".compact-tmp"
+	if err := fresh.Save(tmpPath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath+ngramFileSuffix, path+ngramFileSuffix); err != nil {
+		return err
+	}
+
+	reloaded, err := NewFromFileWithBackend(path, m.newBackend())
+	if err != nil {
+		return err
+	}
+
+	m.base = reloaded
+	m.added = nil
+	m.deleted = make(map[Record]bool)
+	m.deletedAll = make(map[string]bool)
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package recordtrie
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMutableFindMergesOverlay(t *testing.T) {
+	base := NewWithBackend([]Record{
+		{"foo", "bar"},
+		{"foo", "baz"},
+	}, newPatriciaBackend())
+
+	m := NewMutable(base)
+	m.Add(Record{"foo", "qux"})
+
+	if v := m.Find("foo"); !compareStringSlices(v, []string{"bar", "baz", "qux"}) {
+		t.Errorf("MutableRecordTrie.Find(\"foo\"): got %v", v)
+	}
+}
+
+func TestMutableDelete(t *testing.T) {
+	base := NewWithBackend([]Record{
+		{"foo", "bar"},
+		{"foo", "baz"},
+	}, newPatriciaBackend())
+
+	m := NewMutable(base)
+	m.Delete("foo", "bar")
+
+	if v := m.Find("foo"); !compareStringSlices(v, []string{"baz"}) {
+		t.Errorf("MutableRecordTrie.Find(\"foo\") after Delete: got %v", v)
+	}
+
+	if m.Exists("missing") {
+		t.Error("MutableRecordTrie.Exists(\"missing\"): expected false, got true")
+	}
+}
+
+func TestMutableDeleteThenAddDoesNotDuplicate(t *testing.T) {
+	base := NewWithBackend([]Record{
+		{"foo", "bar"},
+	}, newPatriciaBackend())
+
+	m := NewMutable(base)
+	m.Delete("foo", "bar")
+	m.Add(Record{"foo", "bar"})
+
+	if v := m.Find("foo"); !compareStringSlices(v, []string{"bar"}) {
+		t.Errorf("MutableRecordTrie.Find(\"foo\") after Delete+Add of the same record: got %v", v)
+	}
+}
+
+func TestMutableAddExistingRecordDoesNotDuplicate(t *testing.T) {
+	base := NewWithBackend([]Record{
+		{"foo", "bar"},
+	}, newPatriciaBackend())
+
+	m := NewMutable(base)
+	m.Add(Record{"foo", "bar"})
+
+	if v := m.Find("foo"); !compareStringSlices(v, []string{"bar"}) {
+		t.Errorf("MutableRecordTrie.Find(\"foo\") after re-adding a record already in base: got %v", v)
+	}
+}
+
+func TestMutableDeleteAll(t *testing.T) {
+	base := NewWithBackend([]Record{
+		{"foo", "bar"},
+		{"foo", "baz"},
+	}, newPatriciaBackend())
+
+	m := NewMutable(base)
+	m.DeleteAll("foo")
+	m.Add(Record{"foo", "new"})
+
+	if v := m.Find("foo"); !compareStringSlices(v, []string{"new"}) {
+		t.Errorf("MutableRecordTrie.Find(\"foo\") after DeleteAll+Add: got %v", v)
+	}
+}
+
+func TestMutableKeysStartingWith(t *testing.T) {
+	base := NewWithBackend([]Record{
+		{"foo", "bar"},
+		{"foobar", "baz"},
+	}, newPatriciaBackend())
+
+	m := NewMutable(base)
+	m.DeleteAll("foo")
+	m.Add(Record{"football", "x"})
+
+	keys := m.KeysStartingWith("foo")
+	if !compareStringSlices(keys, []string{"foobar", "football"}) {
+		t.Errorf("MutableRecordTrie.KeysStartingWith(\"foo\"): got %v", keys)
+	}
+}
+
+func TestMutableCompact(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "testMutableTrie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFilePath := tmpFile.Name()
+	defer os.Remove(tmpFilePath)
+	defer os.Remove(tmpFilePath + ngramFileSuffix)
+
+	base := NewWithBackend([]Record{
+		{"foo", "bar"},
+	}, newPatriciaBackend())
+	if err := base.Save(tmpFilePath); err != nil {
+		t.Fatal("RecordTrie.Save", err)
+	}
+
+	loadedBase, err := NewFromFileWithBackend(tmpFilePath, newPatriciaBackend())
+	if err != nil {
+		t.Fatal("NewFromFileWithBackend", err)
+	}
+	m := NewMutableWithBackend(loadedBase, func() trieBackend { return newPatriciaBackend() })
+
+	m.Add(Record{"foo", "baz"})
+	m.Delete("foo", "bar")
+
+	if err := m.Compact(tmpFilePath); err != nil {
+		t.Fatal("MutableRecordTrie.Compact", err)
+	}
+
+	if len(m.added) != 0 || len(m.deleted) != 0 {
+		t.Error("MutableRecordTrie.Compact: overlay was not cleared")
+	}
+
+	if v := m.Find("foo"); !compareStringSlices(v, []string{"baz"}) {
+		t.Errorf("MutableRecordTrie.Find(\"foo\") after Compact: got %v", v)
+	}
+
+	reloaded, err := NewFromFileWithBackend(tmpFilePath, newPatriciaBackend())
+	if err != nil {
+		t.Fatal("NewFromFileWithBackend after Compact", err)
+	}
+	if v := reloaded.Find("foo"); !compareStringSlices(v, []string{"baz"}) {
+		t.Errorf("RecordTrie.Find(\"foo\") from reloaded compacted file: got %v", v)
+	}
+}
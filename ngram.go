@@ -0,0 +1,148 @@
+package recordtrie
+
+import (
+	"bufio"
+	"encoding/gob"
+	"os"
+	"strings"
+)
+
+// ngramFileSuffix is appended to the trie's path to name the sidecar file
+// holding its n-gram index, so Save/NewFromFile can persist and load it
+// alongside the primary backend without changing the primary file's format.
+const ngramFileSuffix = ".ngram"
+
+// defaultNgramSize is the n-gram length used when Options.NgramSize is
+// left at zero. Trigrams are the common choice for substring indexes.
+const defaultNgramSize = 3
+
+// ngramIndex maps n-grams of every stored key to the IDs of the keys
+// containing them, so KeysContaining/RecordsContaining can intersect
+// candidate sets instead of scanning every record.
+type ngramIndex struct {
+	N     int
+	Index map[string][]int
+	Keys  []string
+}
+
+func ngramsOf(s string, n int) []string {
+	if len(s) < n {
+		return nil
+	}
+
+	grams := make([]string, 0, len(s)-n+1)
+	for i := 0; i+n <= len(s); i++ {
+		grams = append(grams, s[i:i+n])
+	}
+	return grams
+}
+
+// buildNgramIndex indexes the distinct keys in keys, assigning each one
+// a stable integer ID.
+func buildNgramIndex(keys []string, n int) *ngramIndex {
+	idx := &ngramIndex{N: n, Index: make(map[string][]int)}
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		id := len(idx.Keys)
+		idx.Keys = append(idx.Keys, key)
+
+		for _, gram := range ngramsOf(key, n) {
+			ids := idx.Index[gram]
+			if len(ids) == 0 || ids[len(ids)-1] != id {
+				idx.Index[gram] = append(ids, id)
+			}
+		}
+	}
+
+	return idx
+}
+
+// candidates returns the IDs of keys that might contain substr. When
+// substr is shorter than the index's n-gram size, it falls back to a
+// full scan since no gram can be derived from it.
+func (idx *ngramIndex) candidates(substr string) []int {
+	grams := ngramsOf(substr, idx.N)
+	if len(grams) == 0 {
+		var ids []int
+		for id, key := range idx.Keys {
+			if strings.Contains(key, substr) {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	var common []int
+	for i, gram := range grams {
+		ids, ok := idx.Index[gram]
+		if !ok {
+			return nil
+		}
+
+		if i == 0 {
+			common = ids
+			continue
+		}
+
+		common = intersectSortedInts(common, ids)
+		if len(common) == 0 {
+			return nil
+		}
+	}
+
+	return common
+}
+
+func intersectSortedInts(a, b []int) []int {
+	var out []int
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return out
+}
+
+func (idx *ngramIndex) save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := gob.NewEncoder(w).Encode(idx); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func loadNgramIndex(path string) (*ngramIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	idx := &ngramIndex{}
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
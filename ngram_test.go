@@ -0,0 +1,78 @@
+package recordtrie
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestTrieKeysContaining(t *testing.T) {
+	records := []Record{
+		{"foobar", "a"},
+		{"barbaz", "b"},
+		{"quux", "c"},
+		{"foo", "d"},
+	}
+
+	r := New(records)
+
+	tests := []struct {
+		substr string
+		keys   []string
+	}{
+		{"oba", []string{"foobar"}},
+		{"bar", []string{"foobar", "barbaz"}},
+		{"qu", []string{"quux"}},
+		{"zzz", []string{}},
+	}
+
+	for _, test := range tests {
+		keys := r.KeysContaining(test.substr)
+		if !compareStringSlices(keys, test.keys) {
+			t.Errorf("RecordTrie.KeysContaining(%q): got %v expected %v\n", test.substr,
+				keys, test.keys)
+		}
+	}
+}
+
+func TestTrieRecordsContaining(t *testing.T) {
+	records := []Record{
+		{"foobar", "a"},
+		{"foobar", "e"},
+		{"barbaz", "b"},
+	}
+
+	r := NewWithBackend(records, newPatriciaBackend())
+
+	found := r.RecordsContaining("oob")
+	if len(found) != 2 {
+		t.Errorf("RecordTrie.RecordsContaining(\"oob\"): got %v, expected 2 records", found)
+	}
+}
+
+func TestTrieNgramLoadSave(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "testNgramTrie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFilePath := tmpFile.Name()
+	defer os.Remove(tmpFilePath)
+	defer os.Remove(tmpFilePath + ngramFileSuffix)
+
+	r := New([]Record{
+		{"foobar", "a"},
+	})
+	if err := r.Save(tmpFilePath); err != nil {
+		t.Fatal("RecordTrie.Save", err)
+	}
+
+	r, err = NewFromFile(tmpFilePath)
+	if err != nil {
+		t.Fatal("RecordTrie.NewFromFile", err)
+	}
+
+	keys := r.KeysContaining("oob")
+	if !compareStringSlices(keys, []string{"foobar"}) {
+		t.Errorf("RecordTrie.KeysContaining() after load: got %v", keys)
+	}
+}
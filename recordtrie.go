@@ -9,12 +9,17 @@ package recordtrie
 
 import (
 	"fmt"
-	"github.com/1player/go-marisa"
 	"strings"
 )
 
 type RecordTrie struct {
-	t marisa.Trie
+	backend trieBackend
+	ngrams  *ngramIndex
+
+	// compact is non-nil for RecordTries built with NewCompact, in which
+	// case each trie value is a list of IDs into compact.Values rather
+	// than a value itself.
+	compact *compactStore
 }
 
 type Record struct {
@@ -28,30 +33,104 @@ type Record struct {
 // the keys to be UTF-8 encoded
 const KV_SEPARATOR = "\xFF"
 
-// Create a new RecordTrie from a list of Records
-func New(records []Record) *RecordTrie {
-	r := &RecordTrie{
-		t: marisa.NewTrie(),
+// Options customizes how a RecordTrie is built. The zero Options value
+// builds a RecordTrie the same way New does.
+type Options struct {
+	// Backend is the trieBackend used for the primary key/value trie.
+	// Defaults to the MARISA backend when nil.
+	Backend trieBackend
+
+	// NgramSize is the n-gram length used to index keys for
+	// KeysContaining and RecordsContaining. Defaults to 3 (trigrams)
+	// when zero.
+	NgramSize int
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Backend == nil {
+		opts.Backend = newMarisaBackend()
+	}
+	if opts.NgramSize == 0 {
+		opts.NgramSize = defaultNgramSize
 	}
-	r.build(records)
+	return opts
+}
+
+// Create a new RecordTrie from a list of Records, using the default
+// MARISA-based backend
+func New(records []Record) *RecordTrie {
+	return NewWithOptions(records, Options{})
+}
+
+// Create a new RecordTrie from a list of Records, using the given
+// trieBackend. This is how callers opt into alternative backends, such
+// as the pure-Go patricia trie, on platforms where cgo and the MARISA
+// library aren't available.
+func NewWithBackend(records []Record, backend trieBackend) *RecordTrie {
+	return NewWithOptions(records, Options{Backend: backend})
+}
+
+// Create a new RecordTrie from a list of Records, using the given Options.
+func NewWithOptions(records []Record, opts Options) *RecordTrie {
+	opts = opts.withDefaults()
+
+	r := &RecordTrie{backend: opts.Backend}
+	r.build(records, opts.NgramSize)
 
 	return r
 }
 
-// Create a new RecordTrie from file
-func NewFromFile(path string) (r *RecordTrie, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("%v", r)
+// Create a new RecordTrie from file, using the default MARISA-based backend
+func NewFromFile(path string) (*RecordTrie, error) {
+	return NewFromFileWithBackend(path, newMarisaBackend())
+}
+
+// Create a new RecordTrie from file, loading it with the given trieBackend.
+// The backend must match the one the file was saved with.
+func NewFromFileWithBackend(path string, backend trieBackend) (r *RecordTrie, err error) {
+	compact, trieFilePath, err := openCompactContainer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = backend.Mmap(trieFilePath); err != nil {
+		return nil, err
+	}
+
+	ngrams, err := loadNgramIndex(path + ngramFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	r = &RecordTrie{backend: backend, ngrams: ngrams, compact: compact}
+
+	if compact != nil {
+		if err := validateCompactValues(r); err != nil {
+			return nil, err
 		}
-	}()
+	}
 
-	r = &RecordTrie{
-		t: marisa.NewTrie(),
+	return r, nil
+}
+
+// openCompactContainer auto-detects whether path is a compact container
+// or a plain backend file. It returns the compactStore (nil if plain)
+// and the path the backend itself should be loaded from.
+func openCompactContainer(path string) (*compactStore, string, error) {
+	isCompact, err := isCompactContainer(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !isCompact {
+		return nil, path, nil
+	}
+
+	compact, err := loadCompactContainer(path)
+	if err != nil {
+		return nil, "", err
 	}
-	r.t.Mmap(path)
 
-	return
+	return compact, path + compactTrieFileSuffix, nil
 }
 
 func buildTrieKey(key, value string) string {
@@ -66,31 +145,26 @@ func splitTrieKey(trieKey string) (string, string) {
 	return pieces[0], pieces[1]
 }
 
-func (r *RecordTrie) build(records []Record) {
-	ks := marisa.NewKeyset()
+func (r *RecordTrie) build(records []Record, ngramSize int) {
+	trieKeys := make([]string, len(records))
+	keys := make([]string, len(records))
 
-	for _, record := range records {
-		trieKey := buildTrieKey(record.Key, record.Value)
-		ks.PushBackString(trieKey)
+	for i, record := range records {
+		trieKeys[i] = buildTrieKey(record.Key, record.Value)
+		keys[i] = record.Key
 	}
 
-	r.t.Build(ks)
+	r.backend.Build(trieKeys)
+	r.ngrams = buildNgramIndex(keys, ngramSize)
 }
 
 // Given a trie key prefix, call iterFunc for each matching (key, value) found
 // Stop iterating if iterFunc returns false
 func (r *RecordTrie) iter(query string, iterFunc func(k, v string) bool) {
-	a := marisa.NewAgent()
-	a.SetQueryString(query)
-
-	for r.t.PredictiveSearch(a) {
-		trieKey := a.Key().Str()
+	r.backend.PredictiveSearch(query, func(trieKey string) bool {
 		k, v := splitTrieKey(trieKey)
-
-		if !iterFunc(k, v) {
-			break
-		}
-	}
+		return iterFunc(k, v)
+	})
 }
 
 // Check whether the key exists in the trie
@@ -110,13 +184,32 @@ func (r *RecordTrie) Find(key string) []string {
 	var values []string
 
 	r.iter(buildTrieKey(key, ""), func(k, v string) bool {
-		values = append(values, v)
+		values = append(values, r.resolveValues(v)...)
 		return true
 	})
 
 	return values
 }
 
+// resolveValues turns a trie value into the actual value(s) it denotes.
+// For a plain RecordTrie, the trie value is the value itself. For a
+// compact RecordTrie, it's an encoded list of IDs into r.compact.Values.
+func (r *RecordTrie) resolveValues(trieValue string) []string {
+	if r.compact == nil {
+		return []string{trieValue}
+	}
+
+	// NewFromFileWithBackend validates every key's encoded value IDs up
+	// front, so an error here means the trie was built, not loaded, with
+	// a bad encoding; there's no sane recovery short of treating it as
+	// no values.
+	values, err := r.compact.resolveValueIDs(trieValue)
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
 // Returns the list of all keys starting with the specified prefix
 func (r *RecordTrie) KeysStartingWith(keyPrefix string) []string {
 	var keys []string
@@ -129,26 +222,73 @@ func (r *RecordTrie) KeysStartingWith(keyPrefix string) []string {
 	return keys
 }
 
+// Returns the list of all keys containing the specified substring,
+// anywhere within the key
+func (r *RecordTrie) KeysContaining(substr string) []string {
+	var keys []string
+
+	for _, id := range r.ngrams.candidates(substr) {
+		key := r.ngrams.Keys[id]
+		if strings.Contains(key, substr) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Returns the list of all records whose key contains the specified
+// substring, anywhere within the key
+func (r *RecordTrie) RecordsContaining(substr string) []Record {
+	var records []Record
+
+	for _, id := range r.ngrams.candidates(substr) {
+		key := r.ngrams.Keys[id]
+		if !strings.Contains(key, substr) {
+			continue
+		}
+
+		for _, value := range r.Find(key) {
+			records = append(records, Record{key, value})
+		}
+	}
+
+	return records
+}
+
 // Returns the list of all records stored in the trie
 func (r *RecordTrie) Records() []Record {
 	var records []Record
 
 	r.iter("", func(k, v string) bool {
-		records = append(records, Record{k, v})
+		for _, value := range r.resolveValues(v) {
+			records = append(records, Record{k, value})
+		}
 		return true
 	})
 
 	return records
 }
 
-// Save the trie to file
-func (r *RecordTrie) Save(path string) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("%v", r)
+// Save the trie to file. The n-gram index used by KeysContaining and
+// RecordsContaining is saved alongside it, in a path+".ngram" sidecar file.
+//
+// A compact RecordTrie (see NewCompact) is saved as a small container at
+// path, holding a header and its deduplicated values, with the primary
+// key trie itself saved to a path+".trie" sidecar file.
+func (r *RecordTrie) Save(path string) error {
+	if r.compact == nil {
+		if err := r.backend.Save(path); err != nil {
+			return err
 		}
-	}()
+		return r.ngrams.save(path + ngramFileSuffix)
+	}
 
-	r.t.Save(path)
-	return
+	if err := r.backend.Save(path + compactTrieFileSuffix); err != nil {
+		return err
+	}
+	if err := saveCompactContainer(path, r.compact); err != nil {
+		return err
+	}
+	return r.ngrams.save(path + ngramFileSuffix)
 }